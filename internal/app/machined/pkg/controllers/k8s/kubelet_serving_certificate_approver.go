@@ -0,0 +1,272 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package k8s
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/talos-systems/os-runtime/pkg/controller"
+	"github.com/talos-systems/os-runtime/pkg/resource"
+	"github.com/talos-systems/os-runtime/pkg/state"
+	"go.uber.org/zap"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/talos-systems/talos/pkg/resources/network"
+)
+
+// KubeletServingCertificateApproverController watches pending kubelet-serving
+// CertificateSigningRequests and auto-approves the ones whose requested SANs match the
+// addresses and hostname of the node that created them, so that `kubectl logs`, `exec`,
+// and metrics-server keep working once `machine.kubelet.rotateServerCertificates` switches
+// the kubelet away from its self-signed serving certificate.
+//
+// The controller is only started when rotateServerCertificates is enabled, since
+// auto-approving serving certificates widens the set of identities that can obtain a
+// certificate valid for a node's addresses. Clusters with stricter requirements should
+// keep it disabled and run their own CSR approver (e.g. kubelet-csr-approver) instead.
+// It is not registered unconditionally: RegisterOptionalControllers gates it on
+// rotateServerCertificates and must be called once during the machined controller runtime
+// bootstrap for it to actually run.
+//
+// RBAC: the identity of the client returned by Kubernetes needs a ClusterRole binding
+// granting at least:
+//
+//	rules:
+//	- apiGroups: ["certificates.k8s.io"]
+//	  resources: ["certificatesigningrequests"]
+//	  verbs: ["get", "list", "watch"]
+//	- apiGroups: ["certificates.k8s.io"]
+//	  resources: ["certificatesigningrequests/approval"]
+//	  verbs: ["update"]
+//	- apiGroups: ["certificates.k8s.io"]
+//	  resources: ["signers"]
+//	  resourceNames: ["kubernetes.io/kubelet-serving"]
+//	  verbs: ["approve"]
+type KubeletServingCertificateApproverController struct {
+	// Kubernetes returns a client authenticated against the cluster's admin credentials.
+	Kubernetes func(ctx context.Context) (*kubernetes.Clientset, error)
+}
+
+// Name implements controller.Controller interface.
+func (ctrl *KubeletServingCertificateApproverController) Name() string {
+	return "k8s.KubeletServingCertificateApproverController"
+}
+
+// Inputs implements controller.Controller interface.
+func (ctrl *KubeletServingCertificateApproverController) Inputs() []controller.Input {
+	addressID := network.NodeAddressFilteredID
+	hostnameID := network.HostnameID
+
+	return []controller.Input{
+		{
+			Namespace: network.NamespaceName,
+			Type:      network.NodeAddressType,
+			ID:        &addressID,
+			Kind:      controller.InputWeak,
+		},
+		{
+			Namespace: network.NamespaceName,
+			Type:      network.HostnameStatusType,
+			ID:        &hostnameID,
+			Kind:      controller.InputWeak,
+		},
+	}
+}
+
+// Outputs implements controller.Controller interface.
+func (ctrl *KubeletServingCertificateApproverController) Outputs() []controller.Output {
+	return nil
+}
+
+// kubeletServingCSRPollInterval is how often pending CSRs are re-listed between changes of
+// the node's addresses or hostname.
+const kubeletServingCSRPollInterval = 30 * time.Second
+
+// Run implements controller.Controller interface.
+func (ctrl *KubeletServingCertificateApproverController) Run(ctx context.Context, r controller.Runtime, logger *zap.Logger) error {
+	ticker := time.NewTicker(kubeletServingCSRPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.EventCh():
+		case <-ticker.C:
+		}
+
+		allowedIPs, allowedNames, err := ctrl.nodeIdentities(ctx, r)
+		if err != nil {
+			if state.IsNotFoundError(err) {
+				continue
+			}
+
+			return err
+		}
+
+		clientset, err := ctrl.Kubernetes(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to build Kubernetes client: %w", err)
+		}
+
+		if err = ctrl.approvePendingCSRs(ctx, clientset, allowedIPs, allowedNames, logger); err != nil {
+			return fmt.Errorf("failed to approve kubelet serving CSRs: %w", err)
+		}
+	}
+}
+
+// nodeIdentities returns the set of IP addresses and DNS names a kubelet serving
+// certificate for this node is allowed to carry.
+func (ctrl *KubeletServingCertificateApproverController) nodeIdentities(ctx context.Context, r controller.Runtime) (map[string]struct{}, map[string]struct{}, error) {
+	addrRes, err := r.Get(ctx, resource.NewMetadata(network.NamespaceName, network.NodeAddressType, network.NodeAddressFilteredID, resource.VersionUndefined))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get node addresses: %w", err)
+	}
+
+	nodeAddress, ok := addrRes.(*network.NodeAddress)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected resource type %T for node addresses", addrRes)
+	}
+
+	allowedIPs := make(map[string]struct{}, len(nodeAddress.TypedSpec().Addresses))
+
+	for _, ip := range nodeAddress.TypedSpec().Addresses {
+		allowedIPs[ip.IP().String()] = struct{}{}
+	}
+
+	allowedNames := map[string]struct{}{}
+
+	hostnameRes, err := r.Get(ctx, resource.NewMetadata(network.NamespaceName, network.HostnameStatusType, network.HostnameID, resource.VersionUndefined))
+	if err != nil {
+		if !state.IsNotFoundError(err) {
+			return nil, nil, fmt.Errorf("failed to get node hostname: %w", err)
+		}
+	} else if hostnameStatus, ok := hostnameRes.(*network.HostnameStatus); ok {
+		spec := hostnameStatus.TypedSpec()
+
+		allowedNames[spec.Hostname] = struct{}{}
+
+		if spec.FQDN() != "" {
+			allowedNames[spec.FQDN()] = struct{}{}
+		}
+	}
+
+	return allowedIPs, allowedNames, nil
+}
+
+// approvePendingCSRs lists pending `kubernetes.io/kubelet-serving` CSRs requested by this node
+// and approves the ones whose requested IP and DNS SANs are all identities already owned by the node.
+func (ctrl *KubeletServingCertificateApproverController) approvePendingCSRs(ctx context.Context, clientset *kubernetes.Clientset, allowedIPs, allowedNames map[string]struct{}, logger *zap.Logger) error {
+	csrs, err := clientset.CertificatesV1().CertificateSigningRequests().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, csr := range csrs.Items {
+		csr := csr
+
+		if csr.Spec.SignerName != certificatesv1.KubeletServingSignerName || isApprovedOrDenied(&csr) {
+			continue
+		}
+
+		// the CSR must have been created by this node's kubelet ("system:node:<name>")
+		requester := strings.TrimPrefix(csr.Spec.Username, "system:node:")
+		if _, ok := allowedNames[requester]; !ok || requester == csr.Spec.Username {
+			continue
+		}
+
+		ips, names, err := csrSANs(csr.Spec.Request)
+		if err != nil {
+			logger.Warn("failed to parse kubelet serving CSR", zap.String("csr", csr.Name), zap.Error(err))
+
+			continue
+		}
+
+		if !sansMatchNode(ips, names, allowedIPs, allowedNames) {
+			continue
+		}
+
+		csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+			Type:    certificatesv1.CertificateApproved,
+			Status:  "True",
+			Reason:  "TalosKubeletServingCertApprove",
+			Message: "Approved by the Talos kubelet serving certificate approver",
+		})
+
+		if _, err = clientset.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, &csr, metav1.UpdateOptions{}); err != nil {
+			if apierrors.IsConflict(err) {
+				continue
+			}
+
+			return err
+		}
+
+		logger.Info("approved kubelet serving certificate", zap.String("csr", csr.Name))
+	}
+
+	return nil
+}
+
+func isApprovedOrDenied(csr *certificatesv1.CertificateSigningRequest) bool {
+	for _, c := range csr.Status.Conditions {
+		if c.Type == certificatesv1.CertificateApproved || c.Type == certificatesv1.CertificateDenied {
+			return true
+		}
+	}
+
+	return false
+}
+
+// csrSANs extracts the IP and DNS SANs from a PEM-encoded PKCS#10 certificate request.
+func csrSANs(pemRequest []byte) (ips []string, dnsNames []string, err error) {
+	block, _ := pem.Decode(pemRequest)
+	if block == nil {
+		return nil, nil, fmt.Errorf("failed to decode CSR PEM block")
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CSR: %w", err)
+	}
+
+	ips = make([]string, 0, len(csr.IPAddresses))
+	for _, ip := range csr.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+
+	return ips, csr.DNSNames, nil
+}
+
+// sansMatchNode reports whether every IP and DNS SAN requested by the CSR is an identity
+// the node actually owns. A CSR with no SANs at all, or with even a single SAN that isn't
+// recognized (e.g. an attacker-chosen DNS name riding alongside a legitimate node IP), is
+// rejected.
+func sansMatchNode(ips, dnsNames []string, allowedIPs, allowedNames map[string]struct{}) bool {
+	if len(ips) == 0 && len(dnsNames) == 0 {
+		return false
+	}
+
+	for _, ip := range ips {
+		if _, ok := allowedIPs[ip]; !ok {
+			return false
+		}
+	}
+
+	for _, name := range dnsNames {
+		if _, ok := allowedNames[name]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
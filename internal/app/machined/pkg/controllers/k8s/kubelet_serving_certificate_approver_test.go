@@ -0,0 +1,79 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package k8s
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateCSR(t *testing.T, ips []net.IP, dnsNames []string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: "system:node:test-node"},
+		IPAddresses: ips,
+		DNSNames:    dnsNames,
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func TestCSRSANs(t *testing.T) {
+	t.Parallel()
+
+	csr := generateCSR(t, []net.IP{net.ParseIP("192.168.1.10")}, []string{"node1", "node1.cluster.local"})
+
+	ips, dnsNames, err := csrSANs(csr)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.168.1.10"}, ips)
+	assert.Equal(t, []string{"node1", "node1.cluster.local"}, dnsNames)
+
+	_, _, err = csrSANs([]byte("not a pem block"))
+	assert.Error(t, err)
+}
+
+func TestSansMatchNode(t *testing.T) {
+	t.Parallel()
+
+	allowedIPs := map[string]struct{}{"192.168.1.10": {}}
+	allowedNames := map[string]struct{}{"node1": {}, "node1.cluster.local": {}}
+
+	for _, tt := range []struct {
+		name     string
+		ips      []string
+		dns      []string
+		expected bool
+	}{
+		{"legit IP and DNS", []string{"192.168.1.10"}, []string{"node1"}, true},
+		{"legit IP only", []string{"192.168.1.10"}, nil, true},
+		{"unknown IP", []string{"10.0.0.1"}, nil, false},
+		{"legit IP with attacker DNS SAN", []string{"192.168.1.10"}, []string{"evil.example.com"}, false},
+		{"no SANs at all", nil, nil, false},
+	} {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, sansMatchNode(tt.ips, tt.dns, allowedIPs, allowedNames))
+		})
+	}
+}
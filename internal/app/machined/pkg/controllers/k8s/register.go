@@ -0,0 +1,56 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/talos-systems/os-runtime/pkg/controller"
+	"k8s.io/client-go/kubernetes"
+
+	talosconfig "github.com/talos-systems/talos/pkg/machinery/config"
+)
+
+// ControllerRegistry is the subset of *osruntime.Runtime's API that
+// RegisterOptionalControllers needs, so the gating logic below can be unit tested against a
+// fake registry instead of a real controller runtime.
+type ControllerRegistry interface {
+	RegisterController(ctrl controller.Controller) error
+}
+
+// RegisterOptionalControllers registers the kubelet-related controllers that are gated by
+// machine config rather than always running, so the controller runtime only pays for them
+// when a cluster actually opts in. The caller (the machined controller runtime bootstrap)
+// must invoke this once, alongside the unconditional controller registrations, passing a
+// Config accessor that always returns the current machine config (config can be replaced at
+// runtime via `talosctl edit machineconfig`).
+func RegisterOptionalControllers(ctx context.Context, registry ControllerRegistry, cfg func() talosconfig.Provider, kubernetesClient func(context.Context) (*kubernetes.Clientset, error)) error {
+	current := cfg()
+	if current == nil {
+		return nil
+	}
+
+	// Gated by rotateServerCertificates: auto-approving serving certificates widens the
+	// set of identities that can obtain a certificate valid for a node's addresses.
+	if current.Machine().Kubelet().RotateServerCertificates() {
+		if err := registry.RegisterController(&KubeletServingCertificateApproverController{
+			Kubernetes: kubernetesClient,
+		}); err != nil {
+			return fmt.Errorf("failed to register kubelet serving certificate approver controller: %w", err)
+		}
+	}
+
+	// Gated by staticPodURL being set: nothing to refresh otherwise.
+	if current.Machine().Kubelet().StaticPodURL() != "" {
+		if err := registry.RegisterController(&StaticPodURLRefreshController{
+			Config: cfg,
+		}); err != nil {
+			return fmt.Errorf("failed to register static pod URL refresh controller: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package k8s_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talos-systems/os-runtime/pkg/controller"
+
+	"github.com/talos-systems/talos/internal/app/machined/pkg/controllers/k8s"
+	talosconfig "github.com/talos-systems/talos/pkg/machinery/config"
+)
+
+type fakeRegistry struct {
+	registered []string
+}
+
+func (f *fakeRegistry) RegisterController(ctrl controller.Controller) error {
+	f.registered = append(f.registered, ctrl.Name())
+
+	return nil
+}
+
+type fakeKubelet struct {
+	talosconfig.Kubelet
+
+	rotateServerCertificates bool
+	staticPodURL             string
+}
+
+func (f *fakeKubelet) RotateServerCertificates() bool { return f.rotateServerCertificates }
+func (f *fakeKubelet) StaticPodURL() string           { return f.staticPodURL }
+
+type fakeMachine struct {
+	talosconfig.Machine
+
+	kubelet talosconfig.Kubelet
+}
+
+func (f *fakeMachine) Kubelet() talosconfig.Kubelet { return f.kubelet }
+
+type fakeProvider struct {
+	talosconfig.Provider
+
+	machine talosconfig.Machine
+}
+
+func (f *fakeProvider) Machine() talosconfig.Machine { return f.machine }
+
+func TestRegisterOptionalControllers(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name                     string
+		rotateServerCertificates bool
+		staticPodURL             string
+		expected                 []string
+	}{
+		{
+			name: "nothing enabled",
+		},
+		{
+			name:                     "rotateServerCertificates only",
+			rotateServerCertificates: true,
+			expected:                 []string{"k8s.KubeletServingCertificateApproverController"},
+		},
+		{
+			name:         "staticPodURL only",
+			staticPodURL: "https://example.com/manifest.yaml",
+			expected:     []string{"k8s.StaticPodURLRefreshController"},
+		},
+		{
+			name:                     "both enabled",
+			rotateServerCertificates: true,
+			staticPodURL:             "https://example.com/manifest.yaml",
+			expected: []string{
+				"k8s.KubeletServingCertificateApproverController",
+				"k8s.StaticPodURLRefreshController",
+			},
+		},
+	} {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &fakeProvider{
+				machine: &fakeMachine{
+					kubelet: &fakeKubelet{
+						rotateServerCertificates: tt.rotateServerCertificates,
+						staticPodURL:             tt.staticPodURL,
+					},
+				},
+			}
+
+			registry := &fakeRegistry{}
+
+			err := k8s.RegisterOptionalControllers(context.Background(), registry, func() talosconfig.Provider { return cfg }, nil)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, registry.registered)
+		})
+	}
+}
+
+func TestRegisterOptionalControllersNilConfig(t *testing.T) {
+	t.Parallel()
+
+	registry := &fakeRegistry{}
+
+	err := k8s.RegisterOptionalControllers(context.Background(), registry, func() talosconfig.Provider { return nil }, nil)
+	require.NoError(t, err)
+	assert.Empty(t, registry.registered)
+}
@@ -0,0 +1,72 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package k8s
+
+import (
+	"context"
+	"time"
+
+	"github.com/talos-systems/os-runtime/pkg/controller"
+	"go.uber.org/zap"
+
+	"github.com/talos-systems/talos/internal/pkg/staticpod"
+	talosconfig "github.com/talos-systems/talos/pkg/machinery/config"
+	"github.com/talos-systems/talos/pkg/machinery/constants"
+)
+
+// staticPodURLRefreshInterval mirrors upstream kubelet's manifest URL polling cadence.
+const staticPodURLRefreshInterval = 20 * time.Second
+
+// StaticPodURLRefreshController periodically re-fetches `machine.kubelet.staticPodURL` and
+// rewrites the manifest into the manifests directory, so node-local system pods (log
+// shippers, node-exporters, ...) served from a URL stay up to date without a machine config
+// round-trip. The kubelet picks up the change on its own manifest directory watch. It shares
+// staticpod.FetchAndWrite with the initial PreFunc fetch so both callers stay in sync.
+type StaticPodURLRefreshController struct {
+	Config func() talosconfig.Provider
+}
+
+// Name implements controller.Controller interface.
+func (ctrl *StaticPodURLRefreshController) Name() string {
+	return "k8s.StaticPodURLRefreshController"
+}
+
+// Inputs implements controller.Controller interface.
+func (ctrl *StaticPodURLRefreshController) Inputs() []controller.Input {
+	return nil
+}
+
+// Outputs implements controller.Controller interface.
+func (ctrl *StaticPodURLRefreshController) Outputs() []controller.Output {
+	return nil
+}
+
+// Run implements controller.Controller interface.
+func (ctrl *StaticPodURLRefreshController) Run(ctx context.Context, r controller.Runtime, logger *zap.Logger) error {
+	ticker := time.NewTicker(staticPodURLRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		cfg := ctrl.Config()
+		if cfg == nil {
+			continue
+		}
+
+		url := cfg.Machine().Kubelet().StaticPodURL()
+		if url == "" {
+			continue
+		}
+
+		if err := staticpod.FetchAndWrite(ctx, url, cfg.Machine().Kubelet().StaticPodURLHeader(), constants.ManifestsDirectory); err != nil {
+			logger.Warn("failed to refresh static pod URL manifest", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package runner
+
+// LogParser consumes raw process output line by line, in addition to whatever the
+// configured LoggingManager persists, so callers can tag and inspect log entries
+// in-process (e.g. surfacing recent kubelet errors through a health check) without
+// replacing the existing logging pipeline. Runner implementations that stream process
+// output (e.g. containerd.NewRunner) are responsible for calling ParseLine for every
+// line of output they already forward to LoggingManager.
+type LogParser interface {
+	// ParseLine processes a single line of raw output from the running process.
+	ParseLine(line string)
+}
+
+// WithLogParser sets p as the runner's LogParser, alongside the LoggingManager configured
+// via WithLoggingManager. p only receives lines from a runner that calls ParseLine on it.
+func WithLogParser(p LogParser) Option {
+	return func(o *Options) {
+		o.LogParser = p
+	}
+}
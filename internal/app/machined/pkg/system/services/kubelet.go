@@ -11,18 +11,24 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 	"time"
 
 	containerdapi "github.com/containerd/containerd"
 	"github.com/containerd/containerd/namespaces"
 	"github.com/containerd/containerd/oci"
+	"github.com/mitchellh/mapstructure"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/talos-systems/os-runtime/pkg/resource"
+	"inet.af/netaddr"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 	kubeletconfig "k8s.io/kubelet/config/v1beta1"
+	credentialproviderapi "k8s.io/kubelet/pkg/apis/credentialprovider/v1"
 
 	"github.com/talos-systems/talos/internal/app/machined/pkg/runtime"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/events"
@@ -31,10 +37,13 @@ import (
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/containerd"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/restart"
 	"github.com/talos-systems/talos/internal/pkg/containers/image"
+	"github.com/talos-systems/talos/internal/pkg/klog"
+	"github.com/talos-systems/talos/internal/pkg/staticpod"
 	"github.com/talos-systems/talos/pkg/argsbuilder"
 	"github.com/talos-systems/talos/pkg/conditions"
 	"github.com/talos-systems/talos/pkg/machinery/config/types/v1alpha1/machine"
 	"github.com/talos-systems/talos/pkg/machinery/constants"
+	"github.com/talos-systems/talos/pkg/retry"
 	"github.com/talos-systems/talos/pkg/resources/k8s"
 	"github.com/talos-systems/talos/pkg/resources/network"
 	timeresource "github.com/talos-systems/talos/pkg/resources/time"
@@ -77,9 +86,15 @@ contexts:
     user: kubelet
 `)
 
+// kubeletHealthLogLines is the number of recent error/fatal klog lines surfaced by
+// HealthFunc when the kubelet's /healthz endpoint fails.
+const kubeletHealthLogLines = 5
+
 // Kubelet implements the Service interface. It serves as the concrete type with
 // the required methods.
-type Kubelet struct{}
+type Kubelet struct {
+	logTail *klog.TailBuffer
+}
 
 // ID implements the Service interface.
 func (k *Kubelet) ID(r runtime.Runtime) string {
@@ -97,6 +112,18 @@ func (k *Kubelet) PreFunc(ctx context.Context, r runtime.Runtime) error {
 		return err
 	}
 
+	if err := writeKubeletCredentialProviderConfig(r); err != nil {
+		return err
+	}
+
+	if err := stageKubeletCredentialProviderBins(r); err != nil {
+		return err
+	}
+
+	if err := fetchStaticPodManifest(ctx, r); err != nil {
+		return err
+	}
+
 	if err := os.MkdirAll(filepath.Dir(constants.KubeletCACert), 0o700); err != nil {
 		return err
 	}
@@ -171,6 +198,15 @@ func (k *Kubelet) Runner(r runtime.Runtime) (runner.Runner, error) {
 		{Type: "bind", Destination: "/var/log/pods", Source: "/var/log/pods", Options: []string{"rbind", "rshared", "rw"}},
 	}
 
+	if len(r.Config().Machine().Kubelet().CredentialProviderConfig()) > 0 {
+		mounts = append(mounts, specs.Mount{
+			Type:        "bind",
+			Destination: constants.KubeletCredentialProviderConfig,
+			Source:      constants.KubeletCredentialProviderConfig,
+			Options:     []string{"bind", "ro"},
+		})
+	}
+
 	// Add extra mounts.
 	// TODO(andrewrynhard): We should verify that the mount source is
 	// allowlisted. There is the potential that a user can expose
@@ -188,10 +224,13 @@ func (k *Kubelet) Runner(r runtime.Runtime) (runner.Runner, error) {
 		env = append(env, fmt.Sprintf("%s=%s", key, val))
 	}
 
+	k.logTail = klog.NewTailBuffer(kubeletHealthLogLines)
+
 	return restart.New(containerd.NewRunner(
 		r.Config().Debug() && r.Config().Machine().Type() == machine.TypeJoin, // enable debug logs only for the worker nodes
 		&args,
 		runner.WithLoggingManager(r.Logging()),
+		runner.WithLogParser(k.logTail),
 		runner.WithNamespace(constants.SystemContainerdNamespace),
 		runner.WithContainerImage(r.Config().Machine().Kubelet().Image()),
 		runner.WithEnv(env),
@@ -221,19 +260,37 @@ func (k *Kubelet) HealthFunc(runtime.Runtime) health.Check {
 
 		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
-			return err
+			return k.wrapHealthError(err)
 		}
 		//nolint:errcheck
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("expected HTTP status OK, got %s", resp.Status)
+			return k.wrapHealthError(fmt.Errorf("expected HTTP status OK, got %s", resp.Status))
 		}
 
 		return nil
 	}
 }
 
+// wrapHealthError annotates a health check failure with the last few error lines the
+// kubelet itself logged, so `talosctl health` shows why the kubelet is unhappy instead of
+// just "connection refused". Lines only show up here if the runner (containerd.NewRunner)
+// calls ParseLine on k.logTail, the parser handed to it via runner.WithLogParser; these lines
+// are not exposed over the machined API or forwarded to `machine.logging.destinations`.
+func (k *Kubelet) wrapHealthError(err error) error {
+	if k.logTail == nil {
+		return err
+	}
+
+	lines := k.logTail.Last(kubeletHealthLogLines)
+	if len(lines) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("%w (recent kubelet log lines: %s)", err, strings.Join(lines, "; "))
+}
+
 // HealthSettings implements the HealthcheckedService interface.
 func (k *Kubelet) HealthSettings(runtime.Runtime) *health.Settings {
 	settings := health.DefaultSettings
@@ -242,7 +299,7 @@ func (k *Kubelet) HealthSettings(runtime.Runtime) *health.Settings {
 	return &settings
 }
 
-func newKubeletConfiguration(clusterDNS []string, dnsDomain string) *kubeletconfig.KubeletConfiguration {
+func newKubeletConfiguration(clusterDNS []string, dnsDomain string, rotateServerCertificates bool) *kubeletconfig.KubeletConfiguration {
 	f := false
 	t := true
 
@@ -255,6 +312,7 @@ func newKubeletConfiguration(clusterDNS []string, dnsDomain string) *kubeletconf
 		Address:            "0.0.0.0",
 		Port:               constants.KubeletPort,
 		RotateCertificates: true,
+		ServerTLSBootstrap: rotateServerCertificates,
 		Authentication: kubeletconfig.KubeletAuthentication{
 			X509: kubeletconfig.KubeletX509Authentication{
 				ClientCAFile: constants.KubeletCACert,
@@ -278,6 +336,16 @@ func newKubeletConfiguration(clusterDNS []string, dnsDomain string) *kubeletconf
 	}
 }
 
+// kubeletExtraConfigDenyList holds the KubeletConfiguration fields that Talos manages itself
+// and that `machine.kubelet.extraConfig` is therefore not allowed to override.
+var kubeletExtraConfigDenyList = []string{
+	"staticPodPath",
+	"authentication",
+	"authorization",
+	"clusterDNS",
+	"clusterDomain",
+}
+
 func (k *Kubelet) args(r runtime.Runtime) ([]string, error) {
 	nodename, err := r.NodeName()
 	if err != nil {
@@ -301,6 +369,24 @@ func (k *Kubelet) args(r runtime.Runtime) ([]string, error) {
 		denyListArgs["cloud-provider"] = "external"
 	}
 
+	if len(r.Config().Machine().Kubelet().CredentialProviderConfig()) > 0 {
+		denyListArgs["image-credential-provider-config"] = constants.KubeletCredentialProviderConfig
+		denyListArgs["image-credential-provider-bin-dir"] = constants.KubeletCredentialProviderBinDir
+	}
+
+	nodeIPs, err := chooseNodeIP(context.Background(), r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to choose node IP: %w", err)
+	}
+
+	if len(nodeIPs) > 0 {
+		denyListArgs["node-ip"] = strings.Join(nodeIPs, ",")
+	}
+
+	if sans := r.Config().Machine().Kubelet().ServerCertSANs(); len(sans) > 0 {
+		denyListArgs["server-cert-sans"] = strings.Join(sans, ",")
+	}
+
 	extraArgs := argsbuilder.Args(r.Config().Machine().Kubelet().ExtraArgs())
 
 	for k := range denyListArgs {
@@ -366,7 +452,11 @@ func writeKubeletConfigYaml(r runtime.Runtime) error {
 		dnsServiceIPsString = append(dnsServiceIPsString, dnsIP.String())
 	}
 
-	kubeletConfiguration := newKubeletConfiguration(dnsServiceIPsString, r.Config().Cluster().Network().DNSDomain())
+	kubeletConfiguration := newKubeletConfiguration(dnsServiceIPsString, r.Config().Cluster().Network().DNSDomain(), r.Config().Machine().Kubelet().RotateServerCertificates())
+
+	if err = mergeKubeletExtraConfig(kubeletConfiguration, r.Config().Machine().Kubelet().ExtraConfig()); err != nil {
+		return fmt.Errorf("failed to merge kubelet extraConfig: %w", err)
+	}
 
 	serializer := json.NewSerializerWithOptions(
 		json.DefaultMetaFactory,
@@ -387,3 +477,271 @@ func writeKubeletConfigYaml(r runtime.Runtime) error {
 
 	return ioutil.WriteFile(constants.KubeletConfig, buf.Bytes(), 0o600)
 }
+
+// chooseNodeIP picks the address(es) to pass to the kubelet's `--node-ip` flag by matching
+// the node's addresses, reported by the `network` resource, against the `validSubnets` and
+// `excludeSubnets` CIDR lists configured under `machine.kubelet.nodeIP`. This is analogous to
+// upstream's `ChooseHostInterface`/`ServerAddressByClientCIDRs` behavior for multi-homed hosts.
+// Up to one IPv4 and one IPv6 address are returned to support dual-stack clusters. If neither
+// is configured, only a node address of the same address family as `cluster.controlPlane.endpoint`
+// is returned instead (addresses of the other family are skipped), so a dual-stack node still
+// advertises the address it actually reaches the control plane on; if the endpoint isn't an IP literal (e.g. a DNS name) this default
+// can't be determined either, and no flag is added, leaving the kubelet to fall back to its
+// own hostname-based default.
+func chooseNodeIP(ctx context.Context, r runtime.Runtime) ([]string, error) {
+	nodeIPConfig := r.Config().Machine().Kubelet().NodeIP()
+
+	validSubnets, err := parseSubnets(nodeIPConfig.ValidSubnets())
+	if err != nil {
+		return nil, fmt.Errorf("invalid validSubnets: %w", err)
+	}
+
+	excludeSubnets, err := parseSubnets(nodeIPConfig.ExcludeSubnets())
+	if err != nil {
+		return nil, fmt.Errorf("invalid excludeSubnets: %w", err)
+	}
+
+	var (
+		preferIPv6     bool
+		restrictFamily bool
+	)
+
+	if len(validSubnets) == 0 && len(excludeSubnets) == 0 {
+		endpointIP, ok := endpointIP(r.Config().Cluster().Endpoint())
+		if !ok {
+			return nil, nil
+		}
+
+		restrictFamily = true
+		preferIPv6 = endpointIP.Is6() && !endpointIP.Is4()
+	}
+
+	res, err := r.State().V1Alpha2().Resources().Get(ctx, resource.NewMetadata(network.NamespaceName, network.NodeAddressType, network.NodeAddressFilteredID, resource.VersionUndefined))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node addresses: %w", err)
+	}
+
+	nodeAddress, ok := res.(*network.NodeAddress)
+	if !ok {
+		return nil, fmt.Errorf("unexpected resource type %T for node addresses", res)
+	}
+
+	var v4, v6 string
+
+	for _, addr := range nodeAddress.TypedSpec().Addresses {
+		ip := addr.IP()
+
+		if !subnetsMatch(ip, validSubnets, excludeSubnets) {
+			continue
+		}
+
+		if restrictFamily && (ip.Is6() && !ip.Is4()) != preferIPv6 {
+			continue
+		}
+
+		if ip.Is4() && v4 == "" {
+			v4 = ip.String()
+		}
+
+		if ip.Is6() && !ip.Is4() && v6 == "" {
+			v6 = ip.String()
+		}
+	}
+
+	var nodeIPs []string
+
+	if v4 != "" {
+		nodeIPs = append(nodeIPs, v4)
+	}
+
+	if v6 != "" {
+		nodeIPs = append(nodeIPs, v6)
+	}
+
+	return nodeIPs, nil
+}
+
+// endpointIP resolves the `cluster.controlPlane.endpoint` host to an IP address, used by
+// chooseNodeIP as the default address family hint when no explicit nodeIP config is given.
+// Hostname-based endpoints report !ok, since resolving them here would require a DNS lookup.
+func endpointIP(endpoint *url.URL) (netaddr.IP, bool) {
+	if endpoint == nil {
+		return netaddr.IP{}, false
+	}
+
+	ip, err := netaddr.ParseIP(endpoint.Hostname())
+	if err != nil {
+		return netaddr.IP{}, false
+	}
+
+	return ip, true
+}
+
+func parseSubnets(cidrs []string) ([]netaddr.IPPrefix, error) {
+	subnets := make([]netaddr.IPPrefix, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		subnet, err := netaddr.ParseIPPrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		subnets = append(subnets, subnet)
+	}
+
+	return subnets, nil
+}
+
+func subnetsMatch(ip netaddr.IP, validSubnets, excludeSubnets []netaddr.IPPrefix) bool {
+	for _, subnet := range excludeSubnets {
+		if subnet.Contains(ip) {
+			return false
+		}
+	}
+
+	if len(validSubnets) == 0 {
+		return true
+	}
+
+	for _, subnet := range validSubnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeKubeletExtraConfig deep-merges `machine.kubelet.extraConfig` on top of the base
+// KubeletConfiguration built by Talos, rejecting any deny-listed field that Talos owns,
+// mirroring the argsbuilder.DenylistError pattern used for CLI flags in args() above.
+func mergeKubeletExtraConfig(kubeletConfiguration *kubeletconfig.KubeletConfiguration, extraConfig map[string]interface{}) error {
+	if len(extraConfig) == 0 {
+		return nil
+	}
+
+	for _, field := range kubeletExtraConfigDenyList {
+		if _, ok := extraConfig[field]; ok {
+			return argsbuilder.NewDenylistError(field)
+		}
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           kubeletConfiguration,
+		WeaklyTypedInput: true,
+		TagName:          "json",
+	})
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(extraConfig)
+}
+
+// writeKubeletCredentialProviderConfig renders the CredentialProviderConfig consumed by
+// the kubelet's `--image-credential-provider-config` flag, mapping each entry of
+// `machine.kubelet.credentialProviders` to a CredentialProvider stanza.
+func writeKubeletCredentialProviderConfig(r runtime.Runtime) error {
+	providers := r.Config().Machine().Kubelet().CredentialProviderConfig()
+	if len(providers) == 0 {
+		return nil
+	}
+
+	config := &credentialproviderapi.CredentialProviderConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kubelet.config.k8s.io/v1",
+			Kind:       "CredentialProviderConfig",
+		},
+	}
+
+	for _, provider := range providers {
+		config.Providers = append(config.Providers, credentialproviderapi.CredentialProvider{
+			Name:         provider.Name(),
+			MatchImages:  provider.MatchImages(),
+			DefaultCacheDuration: &metav1.Duration{
+				Duration: provider.CacheDuration(),
+			},
+			APIVersion: "credentialprovider.kubelet.k8s.io/v1",
+			Args:       provider.Args(),
+			Env:        provider.Env(),
+		})
+	}
+
+	serializer := json.NewSerializerWithOptions(
+		json.DefaultMetaFactory,
+		nil,
+		nil,
+		json.SerializerOptions{
+			Yaml:   true,
+			Pretty: true,
+			Strict: true,
+		},
+	)
+
+	var buf bytes.Buffer
+
+	if err := serializer.Encode(config, &buf); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(constants.KubeletCredentialProviderConfig), 0o700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(constants.KubeletCredentialProviderConfig, buf.Bytes(), 0o600)
+}
+
+// stageKubeletCredentialProviderBins copies the configured credential provider plugin
+// binaries into the well-known bin dir that gets bind mounted into the kubelet container,
+// so that the kubelet can exec them without requiring cloud credentials to be baked into Talos.
+func stageKubeletCredentialProviderBins(r runtime.Runtime) error {
+	providers := r.Config().Machine().Kubelet().CredentialProviderConfig()
+	if len(providers) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(constants.KubeletCredentialProviderBinDir, 0o700); err != nil {
+		return err
+	}
+
+	for _, provider := range providers {
+		src := provider.BinPath()
+		if src == "" {
+			continue
+		}
+
+		dst := filepath.Join(constants.KubeletCredentialProviderBinDir, filepath.Base(src))
+
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("failed to read credential provider binary %q: %w", src, err)
+		}
+
+		if err = ioutil.WriteFile(dst, data, 0o755); err != nil {
+			return fmt.Errorf("failed to stage credential provider binary %q: %w", dst, err)
+		}
+	}
+
+	return nil
+}
+
+// fetchStaticPodManifest seeds the initial download of `machine.kubelet.staticPodURL`, retrying
+// with backoff so a transient fetch error at boot doesn't prevent the kubelet from starting.
+// The companion StaticPodURLRefreshController shares staticpod.FetchAndWrite to keep refreshing
+// the manifest periodically once the kubelet is running.
+func fetchStaticPodManifest(ctx context.Context, r runtime.Runtime) error {
+	url := r.Config().Machine().Kubelet().StaticPodURL()
+	if url == "" {
+		return nil
+	}
+
+	return retry.Exponential(30*time.Second, retry.WithUnits(time.Second)).RetryWithContext(ctx, func(ctx context.Context) error {
+		header := r.Config().Machine().Kubelet().StaticPodURLHeader()
+
+		if err := staticpod.FetchAndWrite(ctx, url, header, constants.ManifestsDirectory); err != nil {
+			return retry.ExpectedError(err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeKubeletExtraConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name        string
+		extraConfig map[string]interface{}
+		expectErr   bool
+	}{
+		{
+			name:        "empty",
+			extraConfig: nil,
+		},
+		{
+			name: "sets a non-denylisted field",
+			extraConfig: map[string]interface{}{
+				"maxPods": 250,
+			},
+		},
+		{
+			name: "rejects a denylisted field",
+			extraConfig: map[string]interface{}{
+				"staticPodPath": "/some/other/path",
+			},
+			expectErr: true,
+		},
+		{
+			name: "rejects clusterDNS override",
+			extraConfig: map[string]interface{}{
+				"clusterDNS": []string{"1.1.1.1"},
+			},
+			expectErr: true,
+		},
+	} {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := newKubeletConfiguration([]string{"10.96.0.10"}, "cluster.local", false)
+
+			// original map must not be mutated by the merge, whether it fails or not
+			before := len(tt.extraConfig)
+
+			err := mergeKubeletExtraConfig(cfg, tt.extraConfig)
+
+			if tt.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, before, len(tt.extraConfig))
+		})
+	}
+
+	t.Run("maxPods is applied", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := newKubeletConfiguration([]string{"10.96.0.10"}, "cluster.local", false)
+
+		require.NoError(t, mergeKubeletExtraConfig(cfg, map[string]interface{}{
+			"maxPods": 250,
+		}))
+
+		assert.EqualValues(t, 250, cfg.MaxPods)
+	})
+}
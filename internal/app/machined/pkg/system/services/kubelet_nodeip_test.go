@@ -0,0 +1,68 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package services
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"inet.af/netaddr"
+)
+
+func TestParseSubnets(t *testing.T) {
+	t.Parallel()
+
+	subnets, err := parseSubnets([]string{"10.0.0.0/8", "fd00::/8"})
+	require.NoError(t, err)
+	assert.Len(t, subnets, 2)
+
+	_, err = parseSubnets([]string{"not-a-cidr"})
+	assert.Error(t, err)
+}
+
+func TestSubnetsMatch(t *testing.T) {
+	t.Parallel()
+
+	valid := []netaddr.IPPrefix{netaddr.MustParseIPPrefix("192.168.1.0/24")}
+	exclude := []netaddr.IPPrefix{netaddr.MustParseIPPrefix("192.168.1.128/25")}
+
+	for _, tt := range []struct {
+		name     string
+		ip       string
+		expected bool
+	}{
+		{"matches valid subnet", "192.168.1.10", true},
+		{"excluded even though in valid subnet", "192.168.1.200", false},
+		{"outside valid subnet", "10.0.0.1", false},
+	} {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, subnetsMatch(netaddr.MustParseIP(tt.ip), valid, exclude))
+		})
+	}
+
+	// with no valid subnets configured, anything not excluded matches
+	assert.True(t, subnetsMatch(netaddr.MustParseIP("1.2.3.4"), nil, exclude))
+	assert.False(t, subnetsMatch(netaddr.MustParseIP("192.168.1.200"), nil, exclude))
+}
+
+func TestEndpointIP(t *testing.T) {
+	t.Parallel()
+
+	ip, ok := endpointIP(&url.URL{Host: "10.5.0.1:6443"})
+	require.True(t, ok)
+	assert.Equal(t, "10.5.0.1", ip.String())
+
+	_, ok = endpointIP(&url.URL{Host: "cluster.example.com:6443"})
+	assert.False(t, ok)
+
+	_, ok = endpointIP(nil)
+	assert.False(t, ok)
+}
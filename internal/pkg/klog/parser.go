@@ -0,0 +1,121 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package klog parses kubelet's klog-formatted log lines in-process and keeps a bounded
+// tail of recent entries, so callers (e.g. a service's health check) can surface the
+// reason a process is unhealthy instead of just reporting "connection refused".
+package klog
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Severity is the klog header severity letter (I, W, E, F).
+type Severity byte
+
+// Known klog severities.
+const (
+	SeverityInfo    Severity = 'I'
+	SeverityWarning Severity = 'W'
+	SeverityError   Severity = 'E'
+	SeverityFatal   Severity = 'F'
+)
+
+// Entry is a single parsed klog line.
+type Entry struct {
+	Severity Severity
+	Message  string
+	Raw      string
+}
+
+// klogHeader matches the standard klog header: "I0102 15:04:05.000000    1 file.go:42] message".
+var klogHeader = regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}\.\d{6}\s+\d+ \S+\] (.*)$`)
+
+// Parse extracts the severity and message from a single klog-formatted line. Lines that
+// don't match the klog header (e.g. multi-line stack traces) are returned as INFO entries
+// carrying the raw line as their message.
+func Parse(line string) Entry {
+	if m := klogHeader.FindStringSubmatch(line); m != nil {
+		return Entry{Severity: Severity(m[1][0]), Message: m[2], Raw: line}
+	}
+
+	return Entry{Severity: SeverityInfo, Message: line, Raw: line}
+}
+
+// Parser consumes raw kubelet log output line by line. Implementations are passed to
+// runner.WithLogParser so log entries can be tagged and inspected in-process instead of
+// only being handed off to the logging manager.
+type Parser interface {
+	// ParseLine processes a single line of raw kubelet output.
+	ParseLine(line string)
+}
+
+// TailBuffer keeps a bounded, ring-style tail of the most recent error/fatal klog entries
+// written to it, for surfacing through a health check.
+type TailBuffer struct {
+	mu      sync.Mutex
+	entries []timestampedEntry
+	size    int
+}
+
+type timestampedEntry struct {
+	at time.Time
+	Entry
+}
+
+// NewTailBuffer creates a TailBuffer retaining up to size error-or-worse entries.
+func NewTailBuffer(size int) *TailBuffer {
+	return &TailBuffer{size: size}
+}
+
+// ParseLine implements Parser, retaining the line if it is an error/fatal klog entry.
+func (b *TailBuffer) ParseLine(line string) {
+	entry := Parse(line)
+	if entry.Severity != SeverityError && entry.Severity != SeverityFatal {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries = append(b.entries, timestampedEntry{at: time.Now(), Entry: entry})
+	if len(b.entries) > b.size {
+		b.entries = b.entries[len(b.entries)-b.size:]
+	}
+}
+
+// Write implements io.Writer for callers that feed raw, possibly multi-line, chunks of
+// kubelet output rather than calling ParseLine directly.
+func (b *TailBuffer) Write(p []byte) (int, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(p))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		b.ParseLine(scanner.Text())
+	}
+
+	return len(p), nil
+}
+
+// Last returns up to n of the most recently recorded error/fatal messages, oldest first.
+func (b *TailBuffer) Last(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n > len(b.entries) {
+		n = len(b.entries)
+	}
+
+	lines := make([]string, 0, n)
+
+	for _, e := range b.entries[len(b.entries)-n:] {
+		lines = append(lines, e.Message)
+	}
+
+	return lines
+}
@@ -0,0 +1,68 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package klog_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/talos-systems/talos/internal/pkg/klog"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		line     string
+		severity klog.Severity
+		message  string
+	}{
+		{
+			name:     "error line",
+			line:     `E0726 15:04:05.000000    1 kubelet.go:42] failed to sync pod`,
+			severity: klog.SeverityError,
+			message:  "failed to sync pod",
+		},
+		{
+			name:     "info line",
+			line:     `I0726 15:04:05.000000    1 kubelet.go:42] started syncing pod`,
+			severity: klog.SeverityInfo,
+			message:  "started syncing pod",
+		},
+		{
+			name:     "non-klog line is kept as-is",
+			line:     `panic: runtime error`,
+			severity: klog.SeverityInfo,
+			message:  "panic: runtime error",
+		},
+	} {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			entry := klog.Parse(tt.line)
+			assert.Equal(t, tt.severity, entry.Severity)
+			assert.Equal(t, tt.message, entry.Message)
+		})
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	t.Parallel()
+
+	buf := klog.NewTailBuffer(2)
+
+	buf.ParseLine(`I0726 15:04:05.000000    1 kubelet.go:1] info, dropped`)
+	buf.ParseLine(`E0726 15:04:06.000000    1 kubelet.go:2] first error`)
+	buf.ParseLine(`E0726 15:04:07.000000    1 kubelet.go:3] second error`)
+	buf.ParseLine(`E0726 15:04:08.000000    1 kubelet.go:4] third error`)
+
+	assert.Equal(t, []string{"second error", "third error"}, buf.Last(2))
+	assert.Equal(t, []string{"third error"}, buf.Last(1))
+	assert.Empty(t, klog.NewTailBuffer(2).Last(5))
+}
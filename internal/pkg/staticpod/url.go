@@ -0,0 +1,89 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package staticpod fetches a static pod manifest from a URL and writes it atomically into
+// the kubelet's manifests directory, matching upstream kubelet's URL manifest source. It is
+// shared between the initial PreFunc seed and the periodic refresh controller so the two
+// callers can't drift out of sync.
+package staticpod
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// URLManifestName is the filename the manifest fetched from `machine.kubelet.staticPodURL`
+// is written under in the manifests directory.
+const URLManifestName = "talos-static-pod-url.yaml"
+
+// FetchAndWrite downloads the manifest at url and writes it atomically into dir, so the
+// kubelet never observes a partially-written file.
+func FetchAndWrite(ctx context.Context, url string, header http.Header, dir string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status fetching static pod manifest from %q: %s", url, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if err = validateManifest(data); err != nil {
+		return fmt.Errorf("invalid static pod manifest fetched from %q: %w", url, err)
+	}
+
+	dest := filepath.Join(dir, URLManifestName)
+	tmp := dest + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, dest)
+}
+
+// validateManifest does a best-effort sanity check that data looks like a Pod manifest before
+// it gets written where the kubelet's static pod path watch will pick it up, so a
+// misconfigured staticPodURL (a 200 response serving an HTML error page, say) fails loudly
+// here instead of silently wedging the kubelet's manifest directory watcher.
+func validateManifest(data []byte) error {
+	var manifest struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+	}
+
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("failed to parse manifest as YAML: %w", err)
+	}
+
+	if manifest.Kind != "Pod" {
+		return fmt.Errorf("expected a Pod manifest, got kind %q", manifest.Kind)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package staticpod_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/talos-systems/talos/internal/pkg/staticpod"
+)
+
+func TestFetchAndWrite(t *testing.T) {
+	t.Parallel()
+
+	var gotHeader string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Test")
+
+		_, _ = w.Write([]byte("apiVersion: v1\nkind: Pod\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	header := http.Header{"X-Test": []string{"hello"}}
+
+	require.NoError(t, staticpod.FetchAndWrite(context.Background(), server.URL, header, dir))
+
+	assert.Equal(t, "hello", gotHeader)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, staticpod.URLManifestName))
+	require.NoError(t, err)
+	assert.Equal(t, "apiVersion: v1\nkind: Pod\n", string(data))
+}
+
+func TestFetchAndWriteBadStatus(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := staticpod.FetchAndWrite(context.Background(), server.URL, nil, t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestFetchAndWriteInvalidManifest(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name string
+		body string
+	}{
+		{"not yaml at all", "<html><body>not found</body></html>"},
+		{"valid yaml, wrong kind", "apiVersion: v1\nkind: ConfigMap\n"},
+	} {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			dir := t.TempDir()
+
+			err := staticpod.FetchAndWrite(context.Background(), server.URL, nil, dir)
+			assert.Error(t, err)
+
+			_, statErr := ioutil.ReadFile(filepath.Join(dir, staticpod.URLManifestName))
+			assert.Error(t, statErr, "invalid manifest must not be written to disk")
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"net/http"
+	"time"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// Kubelet defines the requirements for a config that pertains to kubelet related options.
+type Kubelet interface {
+	Image() string
+	ExtraArgs() map[string]string
+	ExtraMounts() []specs.Mount
+	CredentialProviderConfig() []CredentialProvider
+	ExtraConfig() map[string]interface{}
+	NodeIP() KubeletNodeIP
+	ServerCertSANs() []string
+	RotateServerCertificates() bool
+	StaticPodURL() string
+	StaticPodURLHeader() http.Header
+}
+
+// KubeletNodeIP defines the requirements for `machine.kubelet.nodeIP`.
+type KubeletNodeIP interface {
+	ValidSubnets() []string
+	ExcludeSubnets() []string
+}
+
+// CredentialProvider defines a single entry of `machine.kubelet.credentialProviders`.
+type CredentialProvider interface {
+	Name() string
+	MatchImages() []string
+	CacheDuration() time.Duration
+	Args() []string
+	Env() []string
+	BinPath() string
+}
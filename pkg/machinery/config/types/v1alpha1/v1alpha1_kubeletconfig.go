@@ -0,0 +1,142 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package v1alpha1
+
+import (
+	"net/http"
+	"time"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/talos-systems/talos/pkg/machinery/config"
+)
+
+// KubeletConfig represents the kubelet config values.
+type KubeletConfig struct {
+	KubeletImage               string                            `yaml:"image,omitempty"`
+	KubeletExtraArgs           map[string]string                 `yaml:"extraArgs,omitempty"`
+	KubeletExtraMounts         []specs.Mount                      `yaml:"extraMounts,omitempty"`
+	KubeletCredentialProviders []KubeletCredentialProviderConfig `yaml:"credentialProviders,omitempty"`
+	KubeletExtraConfig         map[string]interface{}            `yaml:"extraConfig,omitempty"`
+	KubeletNodeIPConfig        KubeletNodeIPConfig                `yaml:"nodeIP,omitempty"`
+	KubeletServerCertSANs      []string                           `yaml:"serverCertSANs,omitempty"`
+	KubeletRotateServerCerts   bool                               `yaml:"rotateServerCertificates,omitempty"`
+	KubeletStaticPodURL        string                             `yaml:"staticPodURL,omitempty"`
+	KubeletStaticPodURLHeaders map[string][]string                `yaml:"staticPodURLHeader,omitempty"`
+}
+
+// KubeletNodeIPConfig represents the kubelet node IP configuration.
+type KubeletNodeIPConfig struct {
+	KubeletNodeIPValidSubnets   []string `yaml:"validSubnets,omitempty"`
+	KubeletNodeIPExcludeSubnets []string `yaml:"excludeSubnets,omitempty"`
+}
+
+// ValidSubnets implements the config.KubeletNodeIP interface.
+func (k KubeletNodeIPConfig) ValidSubnets() []string {
+	return k.KubeletNodeIPValidSubnets
+}
+
+// ExcludeSubnets implements the config.KubeletNodeIP interface.
+func (k KubeletNodeIPConfig) ExcludeSubnets() []string {
+	return k.KubeletNodeIPExcludeSubnets
+}
+
+// KubeletCredentialProviderConfig represents a single entry of
+// `machine.kubelet.credentialProviders`, mapped to a CredentialProvider stanza in the
+// CredentialProviderConfig consumed by `--image-credential-provider-config`.
+type KubeletCredentialProviderConfig struct {
+	KubeletCredentialProviderName          string        `yaml:"name"`
+	KubeletCredentialProviderMatchImages   []string      `yaml:"matchImages"`
+	KubeletCredentialProviderCacheDuration time.Duration `yaml:"cacheDuration,omitempty"`
+	KubeletCredentialProviderArgs          []string      `yaml:"args,omitempty"`
+	KubeletCredentialProviderEnv           []string      `yaml:"env,omitempty"`
+	KubeletCredentialProviderBinPath       string        `yaml:"binPath"`
+}
+
+// Image implements the config.Kubelet interface.
+func (k *KubeletConfig) Image() string {
+	return k.KubeletImage
+}
+
+// ExtraArgs implements the config.Kubelet interface.
+func (k *KubeletConfig) ExtraArgs() map[string]string {
+	return k.KubeletExtraArgs
+}
+
+// ExtraMounts implements the config.Kubelet interface.
+func (k *KubeletConfig) ExtraMounts() []specs.Mount {
+	return k.KubeletExtraMounts
+}
+
+// CredentialProviderConfig implements the config.Kubelet interface.
+func (k *KubeletConfig) CredentialProviderConfig() []config.CredentialProvider {
+	providers := make([]config.CredentialProvider, len(k.KubeletCredentialProviders))
+
+	for i := range k.KubeletCredentialProviders {
+		providers[i] = &k.KubeletCredentialProviders[i]
+	}
+
+	return providers
+}
+
+// ExtraConfig implements the config.Kubelet interface.
+func (k *KubeletConfig) ExtraConfig() map[string]interface{} {
+	return k.KubeletExtraConfig
+}
+
+// NodeIP implements the config.Kubelet interface.
+func (k *KubeletConfig) NodeIP() config.KubeletNodeIP {
+	return k.KubeletNodeIPConfig
+}
+
+// ServerCertSANs implements the config.Kubelet interface.
+func (k *KubeletConfig) ServerCertSANs() []string {
+	return k.KubeletServerCertSANs
+}
+
+// RotateServerCertificates implements the config.Kubelet interface.
+func (k *KubeletConfig) RotateServerCertificates() bool {
+	return k.KubeletRotateServerCerts
+}
+
+// StaticPodURL implements the config.Kubelet interface.
+func (k *KubeletConfig) StaticPodURL() string {
+	return k.KubeletStaticPodURL
+}
+
+// StaticPodURLHeader implements the config.Kubelet interface.
+func (k *KubeletConfig) StaticPodURLHeader() http.Header {
+	return http.Header(k.KubeletStaticPodURLHeaders)
+}
+
+// Name implements the config.CredentialProvider interface.
+func (k *KubeletCredentialProviderConfig) Name() string {
+	return k.KubeletCredentialProviderName
+}
+
+// MatchImages implements the config.CredentialProvider interface.
+func (k *KubeletCredentialProviderConfig) MatchImages() []string {
+	return k.KubeletCredentialProviderMatchImages
+}
+
+// CacheDuration implements the config.CredentialProvider interface.
+func (k *KubeletCredentialProviderConfig) CacheDuration() time.Duration {
+	return k.KubeletCredentialProviderCacheDuration
+}
+
+// Args implements the config.CredentialProvider interface.
+func (k *KubeletCredentialProviderConfig) Args() []string {
+	return k.KubeletCredentialProviderArgs
+}
+
+// Env implements the config.CredentialProvider interface.
+func (k *KubeletCredentialProviderConfig) Env() []string {
+	return k.KubeletCredentialProviderEnv
+}
+
+// BinPath implements the config.CredentialProvider interface.
+func (k *KubeletCredentialProviderConfig) BinPath() string {
+	return k.KubeletCredentialProviderBinPath
+}
@@ -0,0 +1,19 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package constants
+
+// Kubelet credential provider paths, used by the `machine.kubelet.credentialProviders`
+// integration to render the plugin config and stage the plugin binaries so the kubelet
+// can exec them without cloud credentials being baked into Talos.
+const (
+	// KubeletCredentialProviderConfig is the path the kubelet's
+	// `--image-credential-provider-config` flag points at.
+	KubeletCredentialProviderConfig = "/etc/kubernetes/kubelet-credential-provider-config.yaml"
+
+	// KubeletCredentialProviderBinDir is the path the kubelet's
+	// `--image-credential-provider-bin-dir` flag points at, bind mounted into the kubelet
+	// container from the same path on the host.
+	KubeletCredentialProviderBinDir = "/usr/libexec/kubernetes/credential-provider-bin"
+)